@@ -0,0 +1,33 @@
+// Package agents lets a flow be bound to a named agent definition — a
+// system prompt plus a scoped subset of the available tools — instead of
+// always exposing the full, package-global toolbox to the model.
+package agents
+
+// Agent bundles a name, a system prompt, and the names of the tools it
+// exposes to the model. Providers resolve ToolNames against their own
+// tool definitions to build the actual LLM-facing toolbox, so this
+// package stays free of any provider or langchaingo dependency.
+type Agent struct {
+	Name         string
+	SystemPrompt string
+	ToolNames    []string
+}
+
+var registry = map[string]Agent{}
+
+// Register adds an agent definition to the registry, keyed by its name.
+// Built-in agents register themselves via init(); other agents are added
+// by calling Register from Go code. Agents are not loaded from config.
+func Register(a Agent) {
+	registry[a.Name] = a
+}
+
+// Get looks up a registered agent by name, falling back to "default" if
+// the name isn't registered.
+func Get(name string) Agent {
+	if a, ok := registry[name]; ok {
+		return a
+	}
+
+	return registry["default"]
+}
@@ -0,0 +1,21 @@
+package agents
+
+func init() {
+	Register(Agent{
+		Name:         "coder",
+		SystemPrompt: "You are an expert software engineer. Use the terminal and code tools to complete the task, and ask the user when you need clarification.",
+		ToolNames:    []string{"terminal", "code", "ask", "done"},
+	})
+
+	Register(Agent{
+		Name:         "researcher",
+		SystemPrompt: "You are a research assistant. Use the browser tool to find information, and ask the user when you need clarification.",
+		ToolNames:    []string{"browser", "ask", "done"},
+	})
+
+	Register(Agent{
+		Name:         "default",
+		SystemPrompt: "You are an expert developer.",
+		ToolNames:    []string{"terminal", "browser", "code", "ask", "done"},
+	})
+}
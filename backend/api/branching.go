@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/semanser/ai-coder/database"
+	"github.com/semanser/ai-coder/executor"
+)
+
+type editTaskRequest struct {
+	Task                     database.Task `json:"task"`
+	NewArgs                  string        `json:"newArgs"`
+	NewMessage               string        `json:"newMessage"`
+	FlowRequiresConfirmation bool          `json:"flowRequiresConfirmation"`
+}
+
+// EditTaskHandler is the EditTask(id, newArgs|newMessage) mutation: it
+// returns an edited sibling of Task rather than mutating history in
+// place, so the caller can persist it as a new row and point the flow's
+// active branch at it while the original branch stays intact.
+func EditTaskHandler(w http.ResponseWriter, r *http.Request) {
+	var req editTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, executor.EditTask(req.Task, req.NewArgs, req.NewMessage, req.FlowRequiresConfirmation))
+}
+
+type forkFlowRequest struct {
+	Flow       database.Flow `json:"flow"`
+	FromTaskID int64         `json:"fromTaskId"`
+}
+
+// ForkFlowHandler is the ForkFlow(fromTaskID) mutation: it returns a new
+// flow sharing Flow's task history up to FromTaskID, with its active
+// branch pointer rewound to that point, leaving Flow itself untouched.
+func ForkFlowHandler(w http.ResponseWriter, r *http.Request) {
+	var req forkFlowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, executor.ForkFlow(req.Flow, req.FromTaskID))
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/semanser/ai-coder/database"
+)
+
+type flowSpendResponse struct {
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	CostUsd          float64 `json:"costUsd"`
+	MaxBudgetUsd     float64 `json:"maxBudgetUsd"`
+}
+
+// FlowSpendHandler exposes a flow's running token usage and cost against
+// its configured MaxBudgetUsd, so the UI can show live spend.
+func FlowSpendHandler(w http.ResponseWriter, r *http.Request) {
+	var flow database.Flow
+	if err := json.NewDecoder(r.Body).Decode(&flow); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, flowSpendResponse{
+		PromptTokens:     flow.PromptTokens.Int64,
+		CompletionTokens: flow.CompletionTokens.Int64,
+		CostUsd:          flow.CostUsd.Float64,
+		MaxBudgetUsd:     flow.MaxBudgetUsd.Float64,
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/semanser/ai-coder/database"
+	"github.com/semanser/ai-coder/executor"
+)
+
+type confirmTaskRequest struct {
+	Task       database.Task `json:"task"`
+	NewArgs    string        `json:"newArgs"`
+	NewMessage string        `json:"newMessage"`
+}
+
+// ConfirmTaskHandler approves a task that's pending_confirmation,
+// optionally applying user edits to its args/message first, and
+// transitions it to in_progress so the executor picks it up.
+func ConfirmTaskHandler(w http.ResponseWriter, r *http.Request) {
+	var req confirmTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := executor.Approve(req.Task, req.NewArgs, req.NewMessage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, task)
+}
+
+type rejectTaskRequest struct {
+	Task   database.Task `json:"task"`
+	Reason string        `json:"reason"`
+}
+
+// RejectTaskHandler rejects a task that's pending_confirmation, recording
+// the user's reason so it's fed back to the model as a tool result on the
+// flow's next turn instead of silently dropping the task.
+func RejectTaskHandler(w http.ResponseWriter, r *http.Request) {
+	var req rejectTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := executor.Reject(req.Task, req.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, task)
+}
@@ -0,0 +1,43 @@
+package database
+
+// Branch is a linear path of tasks from a flow's root task down to a
+// chosen leaf. Once EditTask/ForkFlow start creating siblings, a flow's
+// full task history forms a tree, so providers must be given a Branch
+// rather than the raw, possibly-branching task list.
+type Branch []Task
+
+// BuildBranch walks ParentTaskID pointers backwards from leafID through
+// allTasks and returns the resulting root-to-leaf path in chronological
+// order. A task whose ParentTaskID chain loops back on itself (which
+// should never happen, but would otherwise spin forever) stops the walk
+// at the repeated task instead.
+func BuildBranch(allTasks []Task, leafID int64) Branch {
+	byID := make(map[int64]Task, len(allTasks))
+	for _, t := range allTasks {
+		byID[t.ID] = t
+	}
+
+	visited := make(map[int64]bool, len(allTasks))
+
+	var reversed []Task
+	for id := leafID; id != 0 && !visited[id]; {
+		t, ok := byID[id]
+		if !ok {
+			break
+		}
+		visited[id] = true
+		reversed = append(reversed, t)
+
+		if !t.ParentTaskID.Valid {
+			break
+		}
+		id = t.ParentTaskID.Int64
+	}
+
+	branch := make(Branch, len(reversed))
+	for i, t := range reversed {
+		branch[len(reversed)-1-i] = t
+	}
+
+	return branch
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func taskWithParent(id, parentID int64) Task {
+	t := Task{ID: id}
+	if parentID != 0 {
+		t.ParentTaskID = sql.NullInt64{Int64: parentID, Valid: true}
+	}
+	return t
+}
+
+func TestBuildBranchWalksRootToLeaf(t *testing.T) {
+	all := []Task{
+		taskWithParent(1, 0),
+		taskWithParent(2, 1),
+		taskWithParent(3, 2),
+	}
+
+	branch := BuildBranch(all, 3)
+
+	got := make([]int64, len(branch))
+	for i, task := range branch {
+		got[i] = task.ID
+	}
+
+	want := []int64{1, 2, 3}
+	if len(got) != len(want) {
+		t.Fatalf("BuildBranch() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("BuildBranch() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBuildBranchUnknownLeafIsEmpty(t *testing.T) {
+	branch := BuildBranch(nil, 99)
+
+	if len(branch) != 0 {
+		t.Fatalf("BuildBranch() = %v, want empty", branch)
+	}
+}
+
+func TestBuildBranchStopsOnCyclicParentChain(t *testing.T) {
+	// 1 -> 2 -> 1 -> ... should terminate instead of looping forever.
+	all := []Task{
+		taskWithParent(1, 2),
+		taskWithParent(2, 1),
+	}
+
+	done := make(chan Branch, 1)
+	go func() { done <- BuildBranch(all, 1) }()
+
+	select {
+	case branch := <-done:
+		if len(branch) != 2 {
+			t.Fatalf("BuildBranch() = %v, want 2 tasks", branch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("BuildBranch() did not terminate on a cyclic parent chain")
+	}
+}
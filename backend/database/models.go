@@ -18,12 +18,19 @@ type Container struct {
 }
 
 type Flow struct {
-	ID          int64
-	CreatedAt   sql.NullTime
-	UpdatedAt   sql.NullTime
-	Name        sql.NullString
-	Status      sql.NullString
-	ContainerID sql.NullInt64
+	ID                   int64
+	CreatedAt            sql.NullTime
+	UpdatedAt            sql.NullTime
+	Name                 sql.NullString
+	Status               sql.NullString
+	ContainerID          sql.NullInt64
+	RequiresConfirmation sql.NullBool
+	ActiveTaskID         sql.NullInt64
+	AgentName            sql.NullString
+	PromptTokens         sql.NullInt64
+	CompletionTokens     sql.NullInt64
+	CostUsd              sql.NullFloat64
+	MaxBudgetUsd         sql.NullFloat64
 }
 
 type Log struct {
@@ -35,14 +42,18 @@ type Log struct {
 }
 
 type Task struct {
-	ID         int64
-	CreatedAt  sql.NullTime
-	UpdatedAt  sql.NullTime
-	Type       sql.NullString
-	Status     sql.NullString
-	Args       sql.NullString
-	Results    sql.NullString
-	Message    sql.NullString
-	FlowID     sql.NullInt64
-	ToolCallID sql.NullString
+	ID               int64
+	CreatedAt        sql.NullTime
+	UpdatedAt        sql.NullTime
+	Type             sql.NullString
+	Status           sql.NullString
+	Args             sql.NullString
+	Results          sql.NullString
+	Message          sql.NullString
+	FlowID           sql.NullInt64
+	ToolCallID       sql.NullString
+	ParentTaskID     sql.NullInt64
+	PromptTokens     sql.NullInt64
+	CompletionTokens sql.NullInt64
+	CostUsd          sql.NullFloat64
 }
@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"database/sql"
+
+	"github.com/semanser/ai-coder/database"
+	"github.com/semanser/ai-coder/providers"
+)
+
+// EditTask builds a sibling of task with updated args and/or message
+// instead of mutating it in place, so the original branch remains intact.
+// It's a fresh, not-yet-run task: run-specific fields from the original
+// (Results, PromptTokens, CompletionTokens, CostUsd) are cleared rather
+// than copied over, and its status is resolved the same way
+// toolToTask/anthropicToTask resolve a freshly generated task's status,
+// so editing a tool that's configured to never require confirmation
+// (e.g. browser) doesn't force it into the confirmation queue. The
+// caller persists the returned task as a new row and points the flow's
+// active branch (Flow.ActiveTaskID) at it.
+func EditTask(task database.Task, newArgs, newMessage string, flowRequiresConfirmation bool) database.Task {
+	edited := task
+	edited.ID = 0
+	edited.Results = sql.NullString{}
+	edited.PromptTokens = sql.NullInt64{}
+	edited.CompletionTokens = sql.NullInt64{}
+	edited.CostUsd = sql.NullFloat64{}
+
+	if newArgs != "" {
+		edited.Args = database.StringToNullString(newArgs)
+	}
+	if newMessage != "" {
+		edited.Message = database.StringToNullString(newMessage)
+	}
+
+	status := "in_progress"
+	if providers.RequiresConfirmation(task.Type.String, flowRequiresConfirmation) {
+		status = "pending_confirmation"
+	}
+	edited.Status = database.StringToNullString(status)
+
+	return edited
+}
+
+// ForkFlow creates a new flow that shares source's task history up to
+// fromTaskID, with its own active branch pointer rewound to that point.
+// source is left completely untouched; forked's next generated task is
+// parented under fromTaskID, becoming a sibling of whatever task
+// followed it in source's branch. The caller persists forked as a new
+// Flow row (and, since it shares history rather than copying it, no
+// Task rows need to be duplicated).
+func ForkFlow(source database.Flow, fromTaskID int64) database.Flow {
+	forked := source
+	forked.ID = 0
+	forked.ActiveTaskID = sql.NullInt64{Int64: fromTaskID, Valid: true}
+
+	if source.Name.Valid {
+		forked.Name = database.StringToNullString(source.Name.String + " (fork)")
+	}
+
+	return forked
+}
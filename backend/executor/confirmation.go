@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/semanser/ai-coder/database"
+	"github.com/semanser/ai-coder/providers"
+)
+
+// Runnable reports whether a task is ready to be picked up by the executor.
+// Tasks created with status pending_confirmation must wait for a user
+// decision (Approve or Reject below) before they become in_progress.
+func Runnable(task database.Task) bool {
+	return task.Status.String == "in_progress"
+}
+
+// Approve transitions a task that is awaiting user confirmation to
+// in_progress, optionally applying user edits to the args or message before
+// it's picked up by the executor.
+func Approve(task database.Task, newArgs, newMessage string) (database.Task, error) {
+	if task.Status.String != "pending_confirmation" {
+		return task, fmt.Errorf("task %d is not pending confirmation", task.ID)
+	}
+
+	if newArgs != "" {
+		task.Args = database.StringToNullString(newArgs)
+	}
+	if newMessage != "" {
+		task.Message = database.StringToNullString(newMessage)
+	}
+
+	task.Status = database.StringToNullString("in_progress")
+
+	return task, nil
+}
+
+// Reject marks a task awaiting confirmation as rejected, recording the
+// user's reason so it's fed back to the model as a tool result instead of
+// ever reaching the executor.
+func Reject(task database.Task, reason string) (database.Task, error) {
+	if task.Status.String != "pending_confirmation" {
+		return task, fmt.Errorf("task %d is not pending confirmation", task.ID)
+	}
+
+	return providers.RejectTask(task, reason), nil
+}
@@ -0,0 +1,379 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/semanser/ai-coder/database"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	anthropicAPIURL     = "https://api.anthropic.com/v1/messages"
+	anthropicAPIVersion = "2023-06-01"
+	anthropicMaxTokens  = 4096
+)
+
+type AnthropicProvider struct {
+	apiKey string
+	model  string
+}
+
+func (p AnthropicProvider) New() Provider {
+	return AnthropicProvider{
+		apiKey: os.Getenv("ANTHROPIC_API_KEY"),
+		model:  "claude-3-5-sonnet-20241022",
+	}
+}
+
+func (p AnthropicProvider) Name() ProviderType {
+	return ProviderAnthropic
+}
+
+// anthropicTool is the `tools` entry shape expected by the Messages API.
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicContentBlock covers the `text`, `tool_use` and `tool_result`
+// block types we need to read and write.
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+
+	// type == "text"
+	Text string `json:"text,omitempty"`
+
+	// type == "tool_use"
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// type == "tool_result"
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Stream    bool               `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens          int `json:"input_tokens"`
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+	Error      *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func usageFromAnthropic(resp *anthropicResponse) Usage {
+	if resp == nil {
+		return Usage{}
+	}
+
+	return Usage{
+		PromptTokens:     resp.Usage.InputTokens,
+		CompletionTokens: resp.Usage.OutputTokens,
+		CachedTokens:     resp.Usage.CacheReadInputTokens,
+	}
+}
+
+func anthropicToolsFromTools(tools []llms.Tool) []anthropicTool {
+	out := make([]anthropicTool, 0, len(tools))
+
+	for _, t := range tools {
+		if t.Function == nil {
+			continue
+		}
+
+		schema, err := json.Marshal(t.Function.Parameters)
+		if err != nil {
+			log.Printf("failed to marshal schema for tool %s: %v", t.Function.Name, err)
+			continue
+		}
+
+		out = append(out, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	return out
+}
+
+// tasksToAnthropicMessages mirrors tasksToMessages but produces the
+// content-block shape Anthropic's Messages API expects instead of
+// langchaingo's MessageContent. It compacts tasks itself (see Compact in
+// compaction.go) so every caller - NextTask and NextTaskStream alike -
+// gets context-window compaction for free instead of having to remember
+// to call Compact before building a request. The returned Usage is what
+// compacting cost (zero if it didn't happen); the caller must fold it
+// into the Usage it reports for the overall call.
+func tasksToAnthropicMessages(model string, tasks database.Branch, prompt string, summarize func(query string, n int) (string, Usage, error)) ([]anthropicMessage, Usage) {
+	compacted, compactionUsage, err := Compact(ProviderAnthropic, tasks, contextWindowFor(ProviderAnthropic, model), anthropicMaxTokens, summarize)
+	if err != nil {
+		log.Printf("Failed to compact branch, replaying it in full: %v", err)
+	} else {
+		tasks = compacted
+	}
+
+	var messages []anthropicMessage
+
+	for _, task := range tasks {
+		if task.Type.String == "input" {
+			messages = append(messages, anthropicMessage{
+				Role: "user",
+				Content: []anthropicContentBlock{
+					{Type: "text", Text: prompt},
+				},
+			})
+		}
+
+		if task.ToolCallID.String != "" {
+			messages = append(messages, anthropicMessage{
+				Role: "assistant",
+				Content: []anthropicContentBlock{
+					{
+						Type:  "tool_use",
+						ID:    task.ToolCallID.String,
+						Name:  task.Type.String,
+						Input: json.RawMessage(task.Args.String),
+					},
+				},
+			})
+
+			messages = append(messages, anthropicMessage{
+				Role: "user",
+				Content: []anthropicContentBlock{
+					{
+						Type:      "tool_result",
+						ToolUseID: task.ToolCallID.String,
+						Content:   task.Results.String,
+					},
+				},
+			})
+		}
+
+		// This Ask was generated by the agent itself in case of some error (not the model)
+		if task.Type.String == "ask" && task.ToolCallID.String == "" {
+			messages = append(messages, anthropicMessage{
+				Role: "assistant",
+				Content: []anthropicContentBlock{
+					{Type: "text", Text: task.Message.String},
+				},
+			})
+		}
+
+		// Compact replaced a run of earlier tasks with one of these; it
+		// already carries its own "[summary of N prior steps]" tag. Unlike
+		// the generic tasksToMessages path, Anthropic has no leading system
+		// message in Messages (System is its own request field), so a
+		// summary can end up first in the slice - it must be "user", since
+		// the Messages API 400s on a request whose first message is
+		// "assistant".
+		if task.Type.String == "summary" {
+			messages = append(messages, anthropicMessage{
+				Role: "user",
+				Content: []anthropicContentBlock{
+					{Type: "text", Text: task.Message.String},
+				},
+			})
+		}
+	}
+
+	return messages, compactionUsage
+}
+
+// anthropicToTask translates the first tool_use block of a response into a
+// *database.Task, the same way toolToTask does for langchaingo choices.
+func anthropicToTask(resp *anthropicResponse, requiresConfirmation bool) (*database.Task, error) {
+	if resp == nil || len(resp.Content) == 0 {
+		return nil, fmt.Errorf("no content blocks found, asking user")
+	}
+
+	var toolUse *anthropicContentBlock
+	for i := range resp.Content {
+		if resp.Content[i].Type == "tool_use" {
+			toolUse = &resp.Content[i]
+			break
+		}
+	}
+
+	if toolUse == nil {
+		return nil, fmt.Errorf("no tool calls found, asking user")
+	}
+
+	task := database.Task{
+		Type: database.StringToNullString(toolUse.Name),
+	}
+
+	// We use AskArgs to extract the message, same as toolToTask.
+	params, err := extractToolArgs(string(toolUse.Input), &AskArgs{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract args: %v", err)
+	}
+
+	args, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal terminal args, asking user: %v", err)
+	}
+	task.Args = database.StringToNullString(string(args))
+
+	msg := string(params.Message)
+	if msg == "" {
+		msg = string(toolUse.Input)
+	}
+
+	task.Message = database.StringToNullString(msg)
+
+	status := "in_progress"
+	if toolRequiresConfirmation(toolUse.Name, requiresConfirmation) {
+		status = "pending_confirmation"
+	}
+	task.Status = database.StringToNullString(status)
+
+	task.ToolCallID = database.StringToNullString(toolUse.ID)
+
+	return &task, nil
+}
+
+func (p AnthropicProvider) request(body anthropicRequest) (*anthropicResponse, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, anthropicAPIURL, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call anthropic: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	var out anthropicResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+
+	if out.Error != nil {
+		return nil, fmt.Errorf("anthropic error: %s", out.Error.Message)
+	}
+
+	return &out, nil
+}
+
+func (p AnthropicProvider) NextTask(args NextTaskOptions) (*database.Task, Usage) {
+	if budgetExceeded(args.SpentUSD, args.MaxBudgetUSD) {
+		return defaultAskTask(fmt.Sprintf("This flow's budget of $%.2f has been reached", args.MaxBudgetUSD)), Usage{}
+	}
+
+	prompt := fmt.Sprintf("%s Current docker image is %s.", args.Agent.SystemPrompt, args.DockerImage)
+	messages, compactionUsage := tasksToAnthropicMessages(p.model, args.Tasks, prompt, p.Summary)
+
+	resp, err := p.request(anthropicRequest{
+		Model:     p.model,
+		MaxTokens: anthropicMaxTokens,
+		System:    prompt,
+		Messages:  messages,
+		Tools:     anthropicToolsFromTools(agentTools(args.Agent.ToolNames)),
+	})
+	if err != nil {
+		log.Printf("Anthropic request failed: %v", err)
+		return defaultAskTask("There was an error communicating with Anthropic"), compactionUsage
+	}
+
+	task, err := anthropicToTask(resp, args.RequiresConfirmation)
+	if err != nil {
+		log.Printf("Failed to convert anthropic response to task: %v", err)
+		return defaultAskTask("I don't know what to do next"), compactionUsage.Add(usageFromAnthropic(resp))
+	}
+
+	return task, compactionUsage.Add(usageFromAnthropic(resp))
+}
+
+func (p AnthropicProvider) Summary(query string, n int) (string, Usage, error) {
+	prompt := fmt.Sprintf("Summarize the following text in %d words or less: %s", n, query)
+
+	resp, err := p.request(anthropicRequest{
+		Model:     p.model,
+		MaxTokens: anthropicMaxTokens,
+		Messages: []anthropicMessage{
+			{Role: "user", Content: []anthropicContentBlock{{Type: "text", Text: prompt}}},
+		},
+	})
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to get summary: %v", err)
+	}
+
+	return anthropicTextContent(resp), usageFromAnthropic(resp), nil
+}
+
+func (p AnthropicProvider) DockerImageName(task string) (string, Usage, error) {
+	prompt := fmt.Sprintf("Reply with a single docker image name (and nothing else) suitable for running the following task: %s", task)
+
+	resp, err := p.request(anthropicRequest{
+		Model:     p.model,
+		MaxTokens: anthropicMaxTokens,
+		Messages: []anthropicMessage{
+			{Role: "user", Content: []anthropicContentBlock{{Type: "text", Text: prompt}}},
+		},
+	})
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to get docker image name: %v", err)
+	}
+
+	return strings.TrimSpace(anthropicTextContent(resp)), usageFromAnthropic(resp), nil
+}
+
+func anthropicTextContent(resp *anthropicResponse) string {
+	var sb strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	ContentBlock *anthropicContentBlock `json:"content_block"`
+	// Usage is present on "message_delta" (final output_tokens);
+	// "message_start" carries its input_tokens under Message.Usage.
+	Usage   *anthropicUsage `json:"usage,omitempty"`
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+}
+
+// NextTaskStream streams a NextTask call over Anthropic's SSE endpoint,
+// emitting TokenDelta events for assistant text and
+// ToolCallStart/ToolCallArgsDelta/ToolCallEnd events as the tool_use block
+// is built up, followed by a single Done event carrying the same
+// *database.Task NextTask would have returned.
+func (p AnthropicProvider) NextTaskStream(ctx context.Context, args NextTaskOptions) (<-chan Event, error) {
+	if budgetExceeded(args.SpentUSD, args.MaxBudgetUSD) {
+		events := make(chan Event, 1)
+		events <- Event{Type: EventDone, Task: defaultAskTask(fmt.Sprintf("This flow's budget of $%.2f has been reached", args.MaxBudgetUSD))}
+		close(events)
+		return events, nil
+	}
+
+	prompt := fmt.Sprintf("%s Current docker image is %s.", args.Agent.SystemPrompt, args.DockerImage)
+	messages, compactionUsage := tasksToAnthropicMessages(p.model, args.Tasks, prompt, p.Summary)
+
+	body, err := json.Marshal(anthropicRequest{
+		Model:     p.model,
+		MaxTokens: anthropicMaxTokens,
+		System:    prompt,
+		Messages:  messages,
+		Tools:     anthropicToolsFromTools(agentTools(args.Agent.ToolNames)),
+		Stream:    true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicAPIURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call anthropic: %v", err)
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		var toolCallID, toolName string
+		var argsBuilder strings.Builder
+		// Like NextTask, the task is built from the first tool_use block.
+		var firstToolUse *anthropicContentBlock
+		usage := compactionUsage
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			var evt anthropicStreamEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt); err != nil {
+				log.Printf("failed to unmarshal anthropic stream event: %v", err)
+				continue
+			}
+
+			switch evt.Type {
+			case "message_start":
+				if evt.Message != nil {
+					usage.PromptTokens = compactionUsage.PromptTokens + evt.Message.Usage.InputTokens
+					usage.CachedTokens = compactionUsage.CachedTokens + evt.Message.Usage.CacheReadInputTokens
+				}
+			case "message_delta":
+				if evt.Usage != nil {
+					usage.CompletionTokens = compactionUsage.CompletionTokens + evt.Usage.OutputTokens
+				}
+			case "content_block_start":
+				if evt.ContentBlock != nil && evt.ContentBlock.Type == "tool_use" {
+					toolCallID = evt.ContentBlock.ID
+					toolName = evt.ContentBlock.Name
+					argsBuilder.Reset()
+					events <- Event{Type: EventToolCallStart, ToolCallID: toolCallID, ToolName: toolName}
+				}
+			case "content_block_delta":
+				switch evt.Delta.Type {
+				case "text_delta":
+					events <- Event{Type: EventTokenDelta, Text: evt.Delta.Text}
+				case "input_json_delta":
+					argsBuilder.WriteString(evt.Delta.PartialJSON)
+					events <- Event{Type: EventToolCallArgsDelta, ToolCallID: toolCallID, ArgsDelta: evt.Delta.PartialJSON}
+				}
+			case "content_block_stop":
+				if toolCallID != "" {
+					events <- Event{Type: EventToolCallEnd, ToolCallID: toolCallID, ToolName: toolName, ArgsDelta: argsBuilder.String()}
+					if firstToolUse == nil {
+						firstToolUse = &anthropicContentBlock{Type: "tool_use", ID: toolCallID, Name: toolName, Input: json.RawMessage(argsBuilder.String())}
+					}
+					toolCallID = ""
+				}
+			case "message_stop":
+				final := &anthropicResponse{}
+				if firstToolUse != nil {
+					final.Content = []anthropicContentBlock{*firstToolUse}
+				}
+				task, err := anthropicToTask(final, args.RequiresConfirmation)
+				if err != nil {
+					task = defaultAskTask("I don't know what to do next")
+				}
+				events <- Event{Type: EventDone, Task: task, Usage: usage}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			log.Printf("anthropic stream ended with error: %v", err)
+		}
+	}()
+
+	return events, nil
+}
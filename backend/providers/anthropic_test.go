@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/semanser/ai-coder/database"
+)
+
+// wordyTask builds a task whose message is long and varied enough that a
+// BPE tokenizer can't collapse it down to a handful of tokens the way it
+// would a repeated character, so it reliably pushes a branch over
+// defaultContextWindow regardless of tokenizer quirks.
+func wordyTask(id int64) database.Task {
+	var sb strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&sb, "word%d ", i)
+	}
+
+	return database.Task{
+		ID:      id,
+		FlowID:  sql.NullInt64{Int64: 1, Valid: true},
+		Type:    database.StringToNullString("terminal"),
+		Message: database.StringToNullString(sb.String()),
+	}
+}
+
+func TestTasksToAnthropicMessagesPlacesCompactedSummaryFirstAsUser(t *testing.T) {
+	tasks := make(database.Branch, defaultKeepRecentTasks+3)
+	for i := range tasks {
+		tasks[i] = wordyTask(int64(i) + 1)
+	}
+
+	messages, usage := tasksToAnthropicMessages("unknown-model", tasks, "system prompt", func(query string, n int) (string, Usage, error) {
+		return "summarized", Usage{PromptTokens: 50, CompletionTokens: 20}, nil
+	})
+
+	if len(messages) == 0 {
+		t.Fatal("tasksToAnthropicMessages() returned no messages")
+	}
+
+	// The Messages API 400s on a request whose first message isn't "user".
+	if messages[0].Role != "user" {
+		t.Fatalf("messages[0].Role = %q, want %q (compaction must not put an assistant message first)", messages[0].Role, "user")
+	}
+	if len(messages[0].Content) == 0 || !strings.Contains(messages[0].Content[0].Text, "summarized") {
+		t.Fatalf("messages[0].Content = %+v, want it to contain the summarize() output", messages[0].Content)
+	}
+
+	wantUsage := Usage{PromptTokens: 50, CompletionTokens: 20}
+	if usage != wantUsage {
+		t.Fatalf("tasksToAnthropicMessages() usage = %+v, want %+v (the summarize() call's own Usage, so it isn't dropped from the flow's cost rollup)", usage, wantUsage)
+	}
+}
@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/semanser/ai-coder/database"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// defaultKeepRecentTasks is how many of the most recent tasks in a branch
+// are always replayed verbatim, never folded into a summary. It must be
+// large enough to keep every tool_use/tool_result pair in the preserved
+// tail intact, since OpenAI and Anthropic both reject an orphaned
+// tool_use block.
+const defaultKeepRecentTasks = 6
+
+// defaultReservedForCompletion is the headroom left for the model's own
+// reply when a provider doesn't otherwise know its completion budget
+// up front (OpenAI and Ollama call Compact with this; Anthropic uses its
+// actual MaxTokens via anthropicMaxTokens instead).
+const defaultReservedForCompletion = 1024
+
+// estimateTokens approximates the token count of a branch so callers can
+// decide whether it needs compacting before being replayed to the model.
+// OpenAI and Anthropic are both close enough to cl100k_base for a BPE
+// estimate to be meaningful; Ollama's self-hosted models vary too much
+// for that, so they get a plain char-based heuristic instead.
+func estimateTokens(provider ProviderType, tasks database.Branch) int {
+	text := branchText(tasks)
+
+	if provider == ProviderOllama {
+		return len(text) / 4
+	}
+
+	enc, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		return len(text) / 4
+	}
+
+	return len(enc.Encode(text, nil, nil))
+}
+
+func branchText(tasks database.Branch) string {
+	var sb strings.Builder
+	for _, t := range tasks {
+		sb.WriteString(t.Args.String)
+		sb.WriteString(t.Results.String)
+		sb.WriteString(t.Message.String)
+	}
+	return sb.String()
+}
+
+// Compact summarizes the oldest contiguous run of a branch's tasks once it
+// no longer fits in the model's context window, replacing them with a
+// single synthetic Type="summary" task and leaving the most recent
+// defaultKeepRecentTasks tasks untouched. Compact does not persist the
+// summary task, which has no ID; tasksToMessages and
+// tasksToAnthropicMessages use the compacted branch only to build one
+// request and then discard it, so the summary is not stored as a Task row
+// and the next call summarizes the full branch again. Given a branch that
+// already starts with a summary task, Compact folds that summary's text
+// into the new one.
+//
+// summarize is itself a billed model call, so its Usage is returned
+// alongside the compacted branch rather than dropped - callers must fold
+// it into the Usage they return from NextTask/NextTaskStream so compaction
+// traffic counts against a flow's CostUsd and MaxBudgetUSD like any other
+// call.
+func Compact(provider ProviderType, tasks database.Branch, contextWindow, reservedForCompletion int, summarize func(query string, n int) (string, Usage, error)) (database.Branch, Usage, error) {
+	if estimateTokens(provider, tasks) <= contextWindow-reservedForCompletion {
+		return tasks, Usage{}, nil
+	}
+
+	if len(tasks) <= defaultKeepRecentTasks {
+		return tasks, Usage{}, nil
+	}
+
+	toSummarize := tasks[:len(tasks)-defaultKeepRecentTasks]
+	recent := tasks[len(tasks)-defaultKeepRecentTasks:]
+
+	text, usage, err := summarize(branchText(toSummarize), 200)
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to summarize branch: %v", err)
+	}
+
+	summaryTask := database.Task{
+		Type:    database.StringToNullString("summary"),
+		Status:  database.StringToNullString("done"),
+		Message: database.StringToNullString(fmt.Sprintf("[summary of %d prior steps] %s", len(toSummarize), text)),
+		FlowID:  toSummarize[0].FlowID,
+	}
+
+	compacted := make(database.Branch, 0, len(recent)+1)
+	compacted = append(compacted, summaryTask)
+	compacted = append(compacted, recent...)
+
+	return compacted, usage, nil
+}
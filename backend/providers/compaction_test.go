@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/semanser/ai-coder/database"
+)
+
+func longTask(id int64) database.Task {
+	return database.Task{
+		ID:      id,
+		FlowID:  sql.NullInt64{Int64: 1, Valid: true},
+		Type:    database.StringToNullString("terminal"),
+		Message: database.StringToNullString(strings.Repeat("x", 100)),
+	}
+}
+
+func TestCompactLeavesShortBranchUntouched(t *testing.T) {
+	tasks := database.Branch{longTask(1), longTask(2)}
+
+	summarizeCalled := false
+	summarize := func(query string, n int) (string, Usage, error) {
+		summarizeCalled = true
+		return "summary", Usage{}, nil
+	}
+
+	got, usage, err := Compact(ProviderOllama, tasks, 1_000_000, 0, summarize)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if summarizeCalled {
+		t.Fatal("Compact() summarized a branch that already fit the context window")
+	}
+	if len(got) != len(tasks) {
+		t.Fatalf("Compact() = %d tasks, want %d", len(got), len(tasks))
+	}
+	if usage != (Usage{}) {
+		t.Fatalf("Compact() usage = %+v, want zero value when it didn't summarize", usage)
+	}
+}
+
+func TestCompactSkipsWhenNotEnoughTasksToSummarize(t *testing.T) {
+	tasks := make(database.Branch, defaultKeepRecentTasks)
+	for i := range tasks {
+		tasks[i] = longTask(int64(i) + 1)
+	}
+
+	summarizeCalled := false
+	summarize := func(query string, n int) (string, Usage, error) {
+		summarizeCalled = true
+		return "summary", Usage{}, nil
+	}
+
+	// Force "over budget" with a zero context window, but there's nothing
+	// older than defaultKeepRecentTasks to fold into a summary.
+	got, _, err := Compact(ProviderOllama, tasks, 0, 0, summarize)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if summarizeCalled {
+		t.Fatal("Compact() summarized a branch with nothing older than the preserved tail")
+	}
+	if len(got) != len(tasks) {
+		t.Fatalf("Compact() = %d tasks, want %d unchanged", len(got), len(tasks))
+	}
+}
+
+func TestCompactFoldsOldestRunIntoASummaryTask(t *testing.T) {
+	tasks := make(database.Branch, defaultKeepRecentTasks+3)
+	for i := range tasks {
+		tasks[i] = longTask(int64(i) + 1)
+	}
+
+	got, usage, err := Compact(ProviderOllama, tasks, 0, 0, func(query string, n int) (string, Usage, error) {
+		return "summarized", Usage{PromptTokens: 50, CompletionTokens: 20}, nil
+	})
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	wantLen := defaultKeepRecentTasks + 1
+	if len(got) != wantLen {
+		t.Fatalf("Compact() = %d tasks, want %d (1 summary + %d preserved)", len(got), wantLen, defaultKeepRecentTasks)
+	}
+	if got[0].Type.String != "summary" {
+		t.Fatalf("Compact()[0].Type = %q, want %q", got[0].Type.String, "summary")
+	}
+	if !strings.Contains(got[0].Message.String, "summarized") {
+		t.Fatalf("Compact()[0].Message = %q, want it to contain the summarize() output", got[0].Message.String)
+	}
+	wantUsage := Usage{PromptTokens: 50, CompletionTokens: 20}
+	if usage != wantUsage {
+		t.Fatalf("Compact() usage = %+v, want %+v (the summarize() call's own Usage)", usage, wantUsage)
+	}
+
+	// The preserved tail must be the most recent tasks, untouched and in order.
+	for i, task := range got[1:] {
+		want := tasks[len(tasks)-defaultKeepRecentTasks+i].ID
+		if task.ID != want {
+			t.Fatalf("Compact() preserved tail[%d].ID = %d, want %d", i, task.ID, want)
+		}
+	}
+}
+
+func TestCompactPropagatesSummarizeError(t *testing.T) {
+	tasks := make(database.Branch, defaultKeepRecentTasks+1)
+	for i := range tasks {
+		tasks[i] = longTask(int64(i) + 1)
+	}
+
+	_, _, err := Compact(ProviderOllama, tasks, 0, 0, func(query string, n int) (string, Usage, error) {
+		return "", Usage{}, errors.New("summarize failed")
+	})
+	if err == nil {
+		t.Fatal("Compact() error = nil, want non-nil when summarize() fails")
+	}
+}
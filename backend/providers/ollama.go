@@ -0,0 +1,114 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/semanser/ai-coder/database"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/schema"
+)
+
+type OllamaProvider struct {
+	llm   *ollama.LLM
+	model string
+}
+
+func (p OllamaProvider) New() Provider {
+	model := os.Getenv("OLLAMA_MODEL")
+	if model == "" {
+		model = "llama3"
+	}
+
+	llm, err := ollama.New(ollama.WithModel(model))
+	if err != nil {
+		log.Printf("failed to create ollama client: %v", err)
+	}
+
+	return OllamaProvider{llm: llm, model: model}
+}
+
+func (p OllamaProvider) Name() ProviderType {
+	return ProviderOllama
+}
+
+// usageFromOllamaGenerationInfo reads langchaingo's Ollama usage
+// accounting, which surfaces the raw API's prompt_eval_count/eval_count
+// fields on the winning choice's GenerationInfo.
+func usageFromOllamaGenerationInfo(info map[string]any) Usage {
+	u := Usage{}
+	if v, ok := info["PromptEvalCount"].(int); ok {
+		u.PromptTokens = v
+	}
+	if v, ok := info["EvalCount"].(int); ok {
+		u.CompletionTokens = v
+	}
+	return u
+}
+
+// Most Ollama models don't support native function calling, so - like the
+// original textToTask path - we ask the model to emit a JSON tool call in
+// its plain-text reply and parse that back out, rather than relying on
+// llms.ContentChoice.ToolCalls.
+func (p OllamaProvider) NextTask(args NextTaskOptions) (*database.Task, Usage) {
+	if budgetExceeded(args.SpentUSD, args.MaxBudgetUSD) {
+		return defaultAskTask(fmt.Sprintf("This flow's budget of $%.2f has been reached", args.MaxBudgetUSD)), Usage{}
+	}
+
+	prompt := fmt.Sprintf("%s Current docker image is %s.", args.Agent.SystemPrompt, args.DockerImage)
+	messages, compactionUsage := tasksToMessages(ProviderOllama, p.model, args.Tasks, prompt, p.Summary)
+
+	resp, err := p.llm.GenerateContent(context.Background(), messages)
+	if err != nil {
+		log.Printf("Ollama request failed: %v", err)
+		return defaultAskTask("There was an error communicating with Ollama"), compactionUsage
+	}
+
+	if len(resp.Choices) == 0 {
+		log.Printf("Ollama response had no choices")
+		return defaultAskTask("I don't know what to do next"), compactionUsage
+	}
+
+	usage := compactionUsage.Add(usageFromOllamaGenerationInfo(resp.Choices[0].GenerationInfo))
+
+	task, err := textToTask(resp.Choices[0].Content, args.RequiresConfirmation)
+	if err != nil {
+		log.Printf("Failed to convert Ollama response to task: %v", err)
+		return defaultAskTask("I don't know what to do next"), usage
+	}
+
+	return task, usage
+}
+
+func (p OllamaProvider) Summary(query string, n int) (string, Usage, error) {
+	prompt := fmt.Sprintf("Summarize the following text in %d words or less: %s", n, query)
+
+	resp, err := p.llm.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(schema.ChatMessageTypeHuman, prompt)})
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to get summary: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("no choices found in summary response")
+	}
+
+	return resp.Choices[0].Content, usageFromOllamaGenerationInfo(resp.Choices[0].GenerationInfo), nil
+}
+
+func (p OllamaProvider) DockerImageName(task string) (string, Usage, error) {
+	prompt := fmt.Sprintf("Reply with a single docker image name (and nothing else) suitable for running the following task: %s", task)
+
+	resp, err := p.llm.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(schema.ChatMessageTypeHuman, prompt)})
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to get docker image name: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("no choices found in docker image name response")
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Content), usageFromOllamaGenerationInfo(resp.Choices[0].GenerationInfo), nil
+}
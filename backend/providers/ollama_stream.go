@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// NextTaskStream streams a NextTask call via langchaingo's streaming
+// callback, emitting TokenDelta events for each chunk of the model's
+// plain-text reply as it arrives. Since Ollama's tool call is embedded as
+// JSON inside that same text (see textToTask), it can only be parsed once
+// the full reply is in, so ToolCallStart/End and Done are emitted
+// together at the end.
+func (p OllamaProvider) NextTaskStream(ctx context.Context, args NextTaskOptions) (<-chan Event, error) {
+	if budgetExceeded(args.SpentUSD, args.MaxBudgetUSD) {
+		events := make(chan Event, 1)
+		events <- Event{Type: EventDone, Task: defaultAskTask(fmt.Sprintf("This flow's budget of $%.2f has been reached", args.MaxBudgetUSD))}
+		close(events)
+		return events, nil
+	}
+
+	prompt := fmt.Sprintf("%s Current docker image is %s.", args.Agent.SystemPrompt, args.DockerImage)
+	messages, compactionUsage := tasksToMessages(ProviderOllama, p.model, args.Tasks, prompt, p.Summary)
+
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		resp, err := p.llm.GenerateContent(ctx, messages,
+			llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+				events <- Event{Type: EventTokenDelta, Text: string(chunk)}
+				return nil
+			}),
+		)
+		if err != nil {
+			log.Printf("Ollama stream failed: %v", err)
+			events <- Event{Type: EventDone, Task: defaultAskTask("There was an error communicating with Ollama"), Usage: compactionUsage}
+			return
+		}
+
+		if len(resp.Choices) == 0 {
+			log.Printf("Ollama response had no choices")
+			events <- Event{Type: EventDone, Task: defaultAskTask("I don't know what to do next"), Usage: compactionUsage}
+			return
+		}
+
+		usage := compactionUsage.Add(usageFromOllamaGenerationInfo(resp.Choices[0].GenerationInfo))
+
+		task, err := textToTask(resp.Choices[0].Content, args.RequiresConfirmation)
+		if err != nil {
+			task = defaultAskTask("I don't know what to do next")
+		} else {
+			events <- Event{Type: EventToolCallStart, ToolName: task.Type.String}
+			events <- Event{Type: EventToolCallEnd, ToolName: task.Type.String, ArgsDelta: task.Args.String}
+		}
+
+		events <- Event{Type: EventDone, Task: task, Usage: usage}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/semanser/ai-coder/database"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+	"github.com/tmc/langchaingo/schema"
+)
+
+type OpenAIProvider struct {
+	llm   *openai.LLM
+	model string
+}
+
+func (p OpenAIProvider) New() Provider {
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		model = "gpt-4o"
+	}
+
+	llm, err := openai.New(openai.WithToken(os.Getenv("OPENAI_API_KEY")), openai.WithModel(model))
+	if err != nil {
+		log.Printf("failed to create openai client: %v", err)
+	}
+
+	return OpenAIProvider{llm: llm, model: model}
+}
+
+func (p OpenAIProvider) Name() ProviderType {
+	return ProviderOpenAI
+}
+
+// usageFromGenerationInfo reads langchaingo's OpenAI usage accounting,
+// which surfaces the Chat Completions API's "usage" object verbatim on
+// the winning choice's GenerationInfo.
+func usageFromGenerationInfo(info map[string]any) Usage {
+	u := Usage{}
+	if v, ok := info["PromptTokens"].(int); ok {
+		u.PromptTokens = v
+	}
+	if v, ok := info["CompletionTokens"].(int); ok {
+		u.CompletionTokens = v
+	}
+	return u
+}
+
+func (p OpenAIProvider) NextTask(args NextTaskOptions) (*database.Task, Usage) {
+	if budgetExceeded(args.SpentUSD, args.MaxBudgetUSD) {
+		return defaultAskTask(fmt.Sprintf("This flow's budget of $%.2f has been reached", args.MaxBudgetUSD)), Usage{}
+	}
+
+	prompt := fmt.Sprintf("%s Current docker image is %s.", args.Agent.SystemPrompt, args.DockerImage)
+	messages, compactionUsage := tasksToMessages(ProviderOpenAI, p.model, args.Tasks, prompt, p.Summary)
+
+	resp, err := p.llm.GenerateContent(context.Background(), messages, llms.WithTools(agentTools(args.Agent.ToolNames)))
+	if err != nil {
+		log.Printf("OpenAI request failed: %v", err)
+		return defaultAskTask("There was an error communicating with OpenAI"), compactionUsage
+	}
+
+	if len(resp.Choices) == 0 {
+		log.Printf("OpenAI response had no choices")
+		return defaultAskTask("I don't know what to do next"), compactionUsage
+	}
+
+	usage := compactionUsage.Add(usageFromGenerationInfo(resp.Choices[0].GenerationInfo))
+
+	task, err := toolToTask(resp.Choices, args.RequiresConfirmation)
+	if err != nil {
+		log.Printf("Failed to convert OpenAI response to task: %v", err)
+		return defaultAskTask("I don't know what to do next"), usage
+	}
+
+	return task, usage
+}
+
+func (p OpenAIProvider) Summary(query string, n int) (string, Usage, error) {
+	prompt := fmt.Sprintf("Summarize the following text in %d words or less: %s", n, query)
+
+	resp, err := p.llm.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(schema.ChatMessageTypeHuman, prompt)})
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to get summary: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("no choices found in summary response")
+	}
+
+	return resp.Choices[0].Content, usageFromGenerationInfo(resp.Choices[0].GenerationInfo), nil
+}
+
+func (p OpenAIProvider) DockerImageName(task string) (string, Usage, error) {
+	prompt := fmt.Sprintf("Reply with a single docker image name (and nothing else) suitable for running the following task: %s", task)
+
+	resp, err := p.llm.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(schema.ChatMessageTypeHuman, prompt)})
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to get docker image name: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("no choices found in docker image name response")
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Content), usageFromGenerationInfo(resp.Choices[0].GenerationInfo), nil
+}
@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// NextTaskStream streams a NextTask call via langchaingo's streaming
+// callback. langchaingo surfaces OpenAI's token stream as plain text
+// chunks, not interleaved tool-call deltas, so token text streams live as
+// it arrives and the tool call itself is only known once GenerateContent
+// returns; it's emitted as a ToolCallStart, one ToolCallArgsDelta carrying
+// the full arguments and a ToolCallEnd, immediately followed by Done.
+func (p OpenAIProvider) NextTaskStream(ctx context.Context, args NextTaskOptions) (<-chan Event, error) {
+	if budgetExceeded(args.SpentUSD, args.MaxBudgetUSD) {
+		events := make(chan Event, 1)
+		events <- Event{Type: EventDone, Task: defaultAskTask(fmt.Sprintf("This flow's budget of $%.2f has been reached", args.MaxBudgetUSD))}
+		close(events)
+		return events, nil
+	}
+
+	prompt := fmt.Sprintf("%s Current docker image is %s.", args.Agent.SystemPrompt, args.DockerImage)
+	messages, compactionUsage := tasksToMessages(ProviderOpenAI, p.model, args.Tasks, prompt, p.Summary)
+
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		resp, err := p.llm.GenerateContent(ctx, messages,
+			llms.WithTools(agentTools(args.Agent.ToolNames)),
+			llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+				events <- Event{Type: EventTokenDelta, Text: string(chunk)}
+				return nil
+			}),
+		)
+		if err != nil {
+			log.Printf("OpenAI stream failed: %v", err)
+			events <- Event{Type: EventDone, Task: defaultAskTask("There was an error communicating with OpenAI"), Usage: compactionUsage}
+			return
+		}
+
+		if len(resp.Choices) == 0 {
+			log.Printf("OpenAI response had no choices")
+			events <- Event{Type: EventDone, Task: defaultAskTask("I don't know what to do next"), Usage: compactionUsage}
+			return
+		}
+
+		usage := compactionUsage.Add(usageFromGenerationInfo(resp.Choices[0].GenerationInfo))
+
+		if toolCalls := resp.Choices[0].ToolCalls; len(toolCalls) > 0 {
+			tc := toolCalls[0]
+			events <- Event{Type: EventToolCallStart, ToolCallID: tc.ID, ToolName: tc.FunctionCall.Name}
+			events <- Event{Type: EventToolCallArgsDelta, ToolCallID: tc.ID, ArgsDelta: tc.FunctionCall.Arguments}
+			events <- Event{Type: EventToolCallEnd, ToolCallID: tc.ID, ToolName: tc.FunctionCall.Name, ArgsDelta: tc.FunctionCall.Arguments}
+		}
+
+		task, err := toolToTask(resp.Choices, args.RequiresConfirmation)
+		if err != nil {
+			task = defaultAskTask("I don't know what to do next")
+		}
+
+		events <- Event{Type: EventDone, Task: task, Usage: usage}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,73 @@
+package providers
+
+// Usage captures the token accounting reported by a single provider call.
+// It's persisted on the resulting Task and rolled up into the owning
+// Flow's PromptTokens/CompletionTokens/CostUSD columns.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int
+}
+
+// Add combines two Usages, e.g. a completion's Usage with the Usage
+// billed separately for compacting the branch it was generated from.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		CachedTokens:     u.CachedTokens + other.CachedTokens,
+	}
+}
+
+type modelPricing struct {
+	PromptPerKTokens     float64
+	CompletionPerKTokens float64
+	ContextWindow        int
+}
+
+type pricingKey struct {
+	Provider ProviderType
+	Model    string
+}
+
+// pricingTable holds USD-per-1000-tokens pricing keyed by (provider,
+// model), used to turn a Usage into a CostUSD figure for budgeting.
+// Models not listed here cost $0, rather than guessing.
+var pricingTable = map[pricingKey]modelPricing{
+	{ProviderAnthropic, "claude-3-5-sonnet-20241022"}: {PromptPerKTokens: 0.003, CompletionPerKTokens: 0.015, ContextWindow: 200_000},
+	{ProviderAnthropic, "claude-3-haiku-20240307"}:    {PromptPerKTokens: 0.00025, CompletionPerKTokens: 0.00125, ContextWindow: 200_000},
+	{ProviderOpenAI, "gpt-4o"}:                        {PromptPerKTokens: 0.0025, CompletionPerKTokens: 0.01, ContextWindow: 128_000},
+	{ProviderOpenAI, "gpt-4o-mini"}:                   {PromptPerKTokens: 0.00015, CompletionPerKTokens: 0.0006, ContextWindow: 128_000},
+}
+
+// defaultContextWindow is used for providers/models not listed in
+// pricingTable (e.g. self-hosted Ollama models), which vary too much to
+// guess a sane per-model number for.
+const defaultContextWindow = 8192
+
+// contextWindowFor resolves a model's context window, falling back to
+// defaultContextWindow for unlisted models.
+func contextWindowFor(provider ProviderType, model string) int {
+	if p, ok := pricingTable[pricingKey{Provider: provider, Model: model}]; ok && p.ContextWindow > 0 {
+		return p.ContextWindow
+	}
+
+	return defaultContextWindow
+}
+
+// CostUSD prices a Usage against the pricing table, returning 0 for
+// providers/models (e.g. self-hosted Ollama) that aren't priced per token.
+func (u Usage) CostUSD(provider ProviderType, model string) float64 {
+	price, ok := pricingTable[pricingKey{Provider: provider, Model: model}]
+	if !ok {
+		return 0
+	}
+
+	return float64(u.PromptTokens)/1000*price.PromptPerKTokens + float64(u.CompletionTokens)/1000*price.CompletionPerKTokens
+}
+
+// budgetExceeded reports whether a flow with the given MaxBudgetUSD has
+// already spent it. A zero or negative MaxBudgetUSD means no limit.
+func budgetExceeded(spentUSD, maxBudgetUSD float64) bool {
+	return maxBudgetUSD > 0 && spentUSD >= maxBudgetUSD
+}
@@ -0,0 +1,36 @@
+package providers
+
+import "testing"
+
+func TestBudgetExceededAtExactBoundary(t *testing.T) {
+	if !budgetExceeded(5, 5) {
+		t.Fatal("budgetExceeded(5, 5) = false, want true: spending exactly the budget should stop further calls")
+	}
+	if budgetExceeded(4.99, 5) {
+		t.Fatal("budgetExceeded(4.99, 5) = true, want false")
+	}
+}
+
+func TestBudgetExceededNoLimit(t *testing.T) {
+	if budgetExceeded(1_000_000, 0) {
+		t.Fatal("budgetExceeded(_, 0) = true, want false: a zero MaxBudgetUSD means no limit")
+	}
+}
+
+func TestCostUSDUnpricedModelIsFree(t *testing.T) {
+	u := Usage{PromptTokens: 1000, CompletionTokens: 1000}
+
+	if got := u.CostUSD(ProviderOllama, "some-self-hosted-model"); got != 0 {
+		t.Fatalf("CostUSD(unpriced model) = %v, want 0", got)
+	}
+}
+
+func TestCostUSDKnownModel(t *testing.T) {
+	u := Usage{PromptTokens: 1000, CompletionTokens: 1000}
+
+	got := u.CostUSD(ProviderAnthropic, "claude-3-5-sonnet-20241022")
+	want := 0.003 + 0.015
+	if got != want {
+		t.Fatalf("CostUSD() = %v, want %v", got, want)
+	}
+}
@@ -1,11 +1,13 @@
 package providers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 
+	"github.com/semanser/ai-coder/agents"
 	"github.com/semanser/ai-coder/database"
 
 	"github.com/invopop/jsonschema"
@@ -16,21 +18,99 @@ import (
 type ProviderType string
 
 const (
-	ProviderOpenAI ProviderType = "openai"
-	ProviderOllama ProviderType = "ollama"
+	ProviderOpenAI    ProviderType = "openai"
+	ProviderOllama    ProviderType = "ollama"
+	ProviderAnthropic ProviderType = "anthropic"
 )
 
 type Provider interface {
 	New() Provider
 	Name() ProviderType
-	Summary(query string, n int) (string, error)
-	DockerImageName(task string) (string, error)
-	NextTask(args NextTaskOptions) *database.Task
+	Summary(query string, n int) (string, Usage, error)
+	DockerImageName(task string) (string, Usage, error)
+	NextTask(args NextTaskOptions) (*database.Task, Usage)
+	// NextTaskStream is the streaming counterpart of NextTask: it emits
+	// TokenDelta/ToolCallStart/ToolCallArgsDelta/ToolCallEnd events as the
+	// model generates its response, followed by a single Done event
+	// carrying the *database.Task NextTask would have returned. Canceling
+	// ctx stops generation early.
+	NextTaskStream(ctx context.Context, args NextTaskOptions) (<-chan Event, error)
 }
 
 type NextTaskOptions struct {
-	Tasks       []database.Task
-	DockerImage string
+	Tasks                database.Branch
+	DockerImage          string
+	RequiresConfirmation bool
+	Agent                agents.Agent
+
+	// SpentUSD is the flow's running cost so far (the rollup of its
+	// tasks' Usage.CostUSD), and MaxBudgetUSD is the flow's configured
+	// limit. When the budget has been reached, NextTask short-circuits
+	// into a defaultAskTask instead of calling the model.
+	SpentUSD     float64
+	MaxBudgetUSD float64
+}
+
+// agentTools resolves an agent's ToolNames against the full Tools
+// definitions, preserving Tools' order. A flow bound to an agent only
+// ever sees the tools the agent was scoped with, not the package-global
+// Tools slice.
+func agentTools(names []string) []llms.Tool {
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[n] = true
+	}
+
+	var tools []llms.Tool
+	for _, t := range Tools {
+		if t.Function != nil && wanted[t.Function.Name] {
+			tools = append(tools, t)
+		}
+	}
+
+	return tools
+}
+
+// toolConfirmationOverrides forces a tool's confirmation requirement on or
+// off regardless of the flow's default, for tools that are always
+// considered safe (or always considered dangerous) to auto-run.
+var toolConfirmationOverrides = map[string]bool{
+	"terminal": true,
+	"code":     true,
+	"browser":  false,
+}
+
+// toolRequiresConfirmation resolves whether a task for the given tool name
+// should be held for user confirmation, taking the flow's default and any
+// per-tool override into account.
+func toolRequiresConfirmation(toolName string, flowDefault bool) bool {
+	if override, ok := toolConfirmationOverrides[toolName]; ok {
+		return override
+	}
+
+	return flowDefault
+}
+
+// RequiresConfirmation is the exported form of toolRequiresConfirmation,
+// for callers outside this package (e.g. the executor package, when it
+// recomputes a task's status after an edit) that need the same
+// per-tool-override behavior toolToTask and anthropicToTask apply to
+// freshly generated tasks.
+func RequiresConfirmation(toolName string, flowDefault bool) bool {
+	return toolRequiresConfirmation(toolName, flowDefault)
+}
+
+// RejectTask marks a pending_confirmation task as rejected and records the
+// user's reason as the tool's results, so the next call to tasksToMessages
+// feeds the rejection back to the model as a tool_result rather than
+// silently dropping the task. tasksToMessages replays only tasks that have
+// a ToolCallID, so a rejected task parsed by textToTask (Ollama), which
+// has none, is not fed back.
+func RejectTask(task database.Task, reason string) database.Task {
+	task.Status = database.StringToNullString("rejected")
+	task.Results = database.StringToNullString(fmt.Sprintf("User rejected this tool call: %s", reason))
+
+	return task
 }
 
 var Tools = []llms.Tool{
@@ -82,6 +162,8 @@ func ProviderFactory(provider ProviderType) (Provider, error) {
 		return OpenAIProvider{}.New(), nil
 	case ProviderOllama:
 		return OllamaProvider{}.New(), nil
+	case ProviderAnthropic:
+		return AnthropicProvider{}.New(), nil
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", provider)
 	}
@@ -101,7 +183,21 @@ func defaultAskTask(message string) *database.Task {
 	return &task
 }
 
-func tasksToMessages(tasks []database.Task, prompt string) []llms.MessageContent {
+// tasksToMessages builds the langchaingo messages for a provider's
+// NextTask call (OpenAI, Ollama). Like tasksToAnthropicMessages, it
+// compacts tasks itself (see Compact in compaction.go) so compaction
+// can't be forgotten at a call site - it applies uniformly whether the
+// branch is replayed to a hosted model or a self-hosted Ollama one. The
+// returned Usage is what compacting cost (zero if it didn't happen); the
+// caller must fold it into the Usage it reports for the overall call.
+func tasksToMessages(provider ProviderType, model string, tasks database.Branch, prompt string, summarize func(query string, n int) (string, Usage, error)) ([]llms.MessageContent, Usage) {
+	compacted, compactionUsage, err := Compact(provider, tasks, contextWindowFor(provider, model), defaultReservedForCompletion, summarize)
+	if err != nil {
+		log.Printf("Failed to compact branch, replaying it in full: %v", err)
+	} else {
+		tasks = compacted
+	}
+
 	var messages []llms.MessageContent
 	messages = append(messages, llms.MessageContent{
 		Role: schema.ChatMessageTypeSystem,
@@ -156,12 +252,23 @@ func tasksToMessages(tasks []database.Task, prompt string) []llms.MessageContent
 				},
 			})
 		}
+
+		// Compact replaced a run of earlier tasks with one of these; it
+		// already carries its own "[summary of N prior steps]" tag.
+		if task.Type.String == "summary" {
+			messages = append(messages, llms.MessageContent{
+				Role: schema.ChatMessageTypeAI,
+				Parts: []llms.ContentPart{
+					llms.TextPart(task.Message.String),
+				},
+			})
+		}
 	}
 
-	return messages
+	return messages, compactionUsage
 }
 
-func textToTask(text string) (*database.Task, error) {
+func textToTask(text string, requiresConfirmation bool) (*database.Task, error) {
 	c := unmarshalCall(text)
 
 	if c == nil {
@@ -188,7 +295,12 @@ func textToTask(text string) (*database.Task, error) {
 	}
 
 	task.Message = database.StringToNullString(msg)
-	task.Status = database.StringToNullString("in_progress")
+
+	status := "in_progress"
+	if toolRequiresConfirmation(c.Tool, requiresConfirmation) {
+		status = "pending_confirmation"
+	}
+	task.Status = database.StringToNullString(status)
 
 	return &task, nil
 }
@@ -227,7 +339,7 @@ func unmarshalCall(input string) *Call {
 	return nil
 }
 
-func toolToTask(choices []*llms.ContentChoice) (*database.Task, error) {
+func toolToTask(choices []*llms.ContentChoice, requiresConfirmation bool) (*database.Task, error) {
 	if len(choices) == 0 {
 		return nil, fmt.Errorf("no choices found, asking user")
 	}
@@ -266,7 +378,12 @@ func toolToTask(choices []*llms.ContentChoice) (*database.Task, error) {
 	}
 
 	task.Message = database.StringToNullString(msg)
-	task.Status = database.StringToNullString("in_progress")
+
+	status := "in_progress"
+	if toolRequiresConfirmation(tool.FunctionCall.Name, requiresConfirmation) {
+		status = "pending_confirmation"
+	}
+	task.Status = database.StringToNullString(status)
 
 	task.ToolCallID = database.StringToNullString(tool.ID)
 
@@ -0,0 +1,37 @@
+package providers
+
+import (
+	"github.com/semanser/ai-coder/database"
+)
+
+type EventType string
+
+const (
+	EventTokenDelta        EventType = "token_delta"
+	EventToolCallStart     EventType = "tool_call_start"
+	EventToolCallArgsDelta EventType = "tool_call_args_delta"
+	EventToolCallEnd       EventType = "tool_call_end"
+	EventDone              EventType = "done"
+)
+
+// Event is a single increment of a streamed NextTask call, sent on the
+// channel returned by Provider.NextTaskStream so the UI can render
+// partial assistant text and in-progress tool arguments as they arrive,
+// instead of waiting for the whole task to be generated.
+type Event struct {
+	Type EventType
+
+	// Set on EventTokenDelta.
+	Text string
+
+	// Set on EventToolCallStart, EventToolCallArgsDelta and
+	// EventToolCallEnd.
+	ToolCallID string
+	ToolName   string
+	ArgsDelta  string
+
+	// Set on EventDone, the same *database.Task and Usage NextTask would
+	// return.
+	Task  *database.Task
+	Usage Usage
+}